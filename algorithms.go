@@ -0,0 +1,443 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// FixedWindowStore counts requests in fixed-size time windows (e.g. "15 per
+// minute, resetting on the minute"). It's cheap but allows a burst of up to
+// 2x the limit across a window boundary.
+type FixedWindowStore struct {
+	mu       sync.Mutex
+	counters map[string]*fixedWindow
+	limit    int
+	window   time.Duration
+	maxSize  int
+	ttl      time.Duration
+}
+
+type fixedWindow struct {
+	count      int
+	windowedAt time.Time
+	lastSeen   time.Time
+}
+
+// NewFixedWindowStore creates a Store that allows up to limit requests per
+// window, capped at the default visitor limit and idle TTL so a flood of
+// unique identifiers can't grow its map without bound.
+func NewFixedWindowStore(limit int, window time.Duration) *FixedWindowStore {
+	return NewFixedWindowStoreWithLimits(limit, window, defaultMaxVisitors, defaultVisitorTTL)
+}
+
+// NewFixedWindowStoreWithLimits is NewFixedWindowStore with an explicit
+// visitor cap and idle TTL.
+func NewFixedWindowStoreWithLimits(limit int, window time.Duration, maxVisitors int, ttl time.Duration) *FixedWindowStore {
+	return &FixedWindowStore{
+		counters: make(map[string]*fixedWindow),
+		limit:    limit,
+		window:   window,
+		maxSize:  maxVisitors,
+		ttl:      ttl,
+	}
+}
+
+// Allow implements Store.
+func (s *FixedWindowStore) Allow(key string) (Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	fw, exists := s.counters[key]
+	if !exists || now.Sub(fw.windowedAt) >= s.window {
+		if !exists && len(s.counters) >= s.maxSize {
+			s.evictOldestLocked()
+		}
+		fw = &fixedWindow{windowedAt: now}
+		s.counters[key] = fw
+	}
+	fw.lastSeen = now
+
+	resetAfter := s.window - now.Sub(fw.windowedAt)
+	if fw.count >= s.limit {
+		return Decision{Limit: s.limit, RetryAfter: resetAfter}, nil
+	}
+	fw.count++
+	return Decision{Allowed: true, Limit: s.limit, Remaining: s.limit - fw.count}, nil
+}
+
+// Reset implements Store.
+func (s *FixedWindowStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.counters, key)
+	return nil
+}
+
+// evictOldestLocked removes the least-recently-used entry. The caller must
+// hold s.mu.
+func (s *FixedWindowStore) evictOldestLocked() {
+	var oldestKey string
+	var oldestAt time.Time
+	for k, fw := range s.counters {
+		if oldestKey == "" || fw.lastSeen.Before(oldestAt) {
+			oldestKey, oldestAt = k, fw.lastSeen
+		}
+	}
+	if oldestKey != "" {
+		delete(s.counters, oldestKey)
+	}
+}
+
+// Sweeper periodically evicts entries idle for longer than s.ttl.
+func (s *FixedWindowStore) Sweeper(interval time.Duration) {
+	t := time.NewTicker(interval)
+	for range t.C {
+		cutoff := time.Now().Add(-s.ttl)
+		s.mu.Lock()
+		for k, fw := range s.counters {
+			if fw.lastSeen.Before(cutoff) {
+				delete(s.counters, k)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// SlidingWindowCounterStore approximates a sliding window by weighting the
+// previous fixed window's count by the fraction of it still "inside" the
+// current window, avoiding the boundary-burst problem of FixedWindowStore
+// without the memory cost of SlidingWindowLogStore.
+type SlidingWindowCounterStore struct {
+	mu       sync.Mutex
+	counters map[string]*slidingWindowCounter
+	limit    int
+	window   time.Duration
+	maxSize  int
+	ttl      time.Duration
+}
+
+type slidingWindowCounter struct {
+	prevCount int
+	currCount int
+	currStart time.Time
+	lastSeen  time.Time
+}
+
+// NewSlidingWindowCounterStore creates a Store that allows up to limit
+// requests per sliding window of the given duration, capped at the default
+// visitor limit and idle TTL so a flood of unique identifiers can't grow
+// its map without bound.
+func NewSlidingWindowCounterStore(limit int, window time.Duration) *SlidingWindowCounterStore {
+	return NewSlidingWindowCounterStoreWithLimits(limit, window, defaultMaxVisitors, defaultVisitorTTL)
+}
+
+// NewSlidingWindowCounterStoreWithLimits is NewSlidingWindowCounterStore
+// with an explicit visitor cap and idle TTL.
+func NewSlidingWindowCounterStoreWithLimits(limit int, window time.Duration, maxVisitors int, ttl time.Duration) *SlidingWindowCounterStore {
+	return &SlidingWindowCounterStore{
+		counters: make(map[string]*slidingWindowCounter),
+		limit:    limit,
+		window:   window,
+		maxSize:  maxVisitors,
+		ttl:      ttl,
+	}
+}
+
+// Allow implements Store.
+func (s *SlidingWindowCounterStore) Allow(key string) (Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	c, exists := s.counters[key]
+	if !exists {
+		if len(s.counters) >= s.maxSize {
+			s.evictOldestLocked()
+		}
+		c = &slidingWindowCounter{currStart: now}
+		s.counters[key] = c
+	}
+	c.lastSeen = now
+
+	elapsed := now.Sub(c.currStart)
+	if elapsed >= s.window {
+		windows := int64(elapsed / s.window)
+		if windows == 1 {
+			c.prevCount = c.currCount
+		} else {
+			c.prevCount = 0
+		}
+		c.currCount = 0
+		c.currStart = c.currStart.Add(time.Duration(windows) * s.window)
+		elapsed = now.Sub(c.currStart)
+	}
+
+	weight := float64(s.window-elapsed) / float64(s.window)
+	estimate := float64(c.prevCount)*weight + float64(c.currCount)
+
+	if estimate+1 > float64(s.limit) {
+		return Decision{Limit: s.limit, RetryAfter: s.window - elapsed}, nil
+	}
+	c.currCount++
+	return Decision{Allowed: true, Limit: s.limit, Remaining: s.limit - int(estimate) - 1}, nil
+}
+
+// Reset implements Store.
+func (s *SlidingWindowCounterStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.counters, key)
+	return nil
+}
+
+// evictOldestLocked removes the least-recently-used entry. The caller must
+// hold s.mu.
+func (s *SlidingWindowCounterStore) evictOldestLocked() {
+	var oldestKey string
+	var oldestAt time.Time
+	for k, c := range s.counters {
+		if oldestKey == "" || c.lastSeen.Before(oldestAt) {
+			oldestKey, oldestAt = k, c.lastSeen
+		}
+	}
+	if oldestKey != "" {
+		delete(s.counters, oldestKey)
+	}
+}
+
+// Sweeper periodically evicts entries idle for longer than s.ttl.
+func (s *SlidingWindowCounterStore) Sweeper(interval time.Duration) {
+	t := time.NewTicker(interval)
+	for range t.C {
+		cutoff := time.Now().Add(-s.ttl)
+		s.mu.Lock()
+		for k, c := range s.counters {
+			if c.lastSeen.Before(cutoff) {
+				delete(s.counters, k)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// SlidingWindowLogStore keeps a timestamp per request in the current window
+// and counts how many fall within it. It's the most accurate algorithm but
+// costs O(limit) memory and time per key.
+type SlidingWindowLogStore struct {
+	mu      sync.Mutex
+	logs    map[string]*slidingLog
+	limit   int
+	window  time.Duration
+	maxSize int
+	ttl     time.Duration
+}
+
+type slidingLog struct {
+	times    []time.Time
+	lastSeen time.Time
+}
+
+// NewSlidingWindowLogStore creates a Store that allows up to limit requests
+// within any window-length interval, capped at the default visitor limit
+// and idle TTL so a flood of unique identifiers can't grow its map without
+// bound.
+func NewSlidingWindowLogStore(limit int, window time.Duration) *SlidingWindowLogStore {
+	return NewSlidingWindowLogStoreWithLimits(limit, window, defaultMaxVisitors, defaultVisitorTTL)
+}
+
+// NewSlidingWindowLogStoreWithLimits is NewSlidingWindowLogStore with an
+// explicit visitor cap and idle TTL.
+func NewSlidingWindowLogStoreWithLimits(limit int, window time.Duration, maxVisitors int, ttl time.Duration) *SlidingWindowLogStore {
+	return &SlidingWindowLogStore{
+		logs:    make(map[string]*slidingLog),
+		limit:   limit,
+		window:  window,
+		maxSize: maxVisitors,
+		ttl:     ttl,
+	}
+}
+
+// Allow implements Store.
+func (s *SlidingWindowLogStore) Allow(key string) (Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-s.window)
+
+	l, exists := s.logs[key]
+	if !exists {
+		if len(s.logs) >= s.maxSize {
+			s.evictOldestLocked()
+		}
+		l = &slidingLog{}
+		s.logs[key] = l
+	}
+	l.lastSeen = now
+
+	kept := l.times[:0]
+	for _, t := range l.times {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+
+	if len(kept) >= s.limit {
+		l.times = kept
+		// The request is allowed again once the oldest entry in the
+		// window ages out.
+		return Decision{Limit: s.limit, RetryAfter: kept[0].Add(s.window).Sub(now)}, nil
+	}
+
+	l.times = append(kept, now)
+	return Decision{Allowed: true, Limit: s.limit, Remaining: s.limit - len(kept) - 1}, nil
+}
+
+// Reset implements Store.
+func (s *SlidingWindowLogStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.logs, key)
+	return nil
+}
+
+// evictOldestLocked removes the least-recently-used entry. The caller must
+// hold s.mu.
+func (s *SlidingWindowLogStore) evictOldestLocked() {
+	var oldestKey string
+	var oldestAt time.Time
+	for k, l := range s.logs {
+		if oldestKey == "" || l.lastSeen.Before(oldestAt) {
+			oldestKey, oldestAt = k, l.lastSeen
+		}
+	}
+	if oldestKey != "" {
+		delete(s.logs, oldestKey)
+	}
+}
+
+// Sweeper periodically evicts entries idle for longer than s.ttl.
+func (s *SlidingWindowLogStore) Sweeper(interval time.Duration) {
+	t := time.NewTicker(interval)
+	for range t.C {
+		cutoff := time.Now().Add(-s.ttl)
+		s.mu.Lock()
+		for k, l := range s.logs {
+			if l.lastSeen.Before(cutoff) {
+				delete(s.logs, k)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// LeakyBucketStore models each key as a bucket that fills with incoming
+// requests and leaks at a constant rate, rejecting requests that would
+// overflow capacity. Unlike token bucket, it smooths bursts into a steady
+// outflow instead of allowing them through immediately.
+type LeakyBucketStore struct {
+	mu       sync.Mutex
+	buckets  map[string]*leakyBucket
+	capacity float64
+	leakRate float64 // units per second
+	maxSize  int
+	ttl      time.Duration
+}
+
+type leakyBucket struct {
+	level    float64
+	lastLeak time.Time
+}
+
+// NewLeakyBucketStore creates a Store with the given capacity and leak rate
+// (units per second), capped at the default visitor limit and idle TTL so a
+// flood of unique identifiers can't grow its map without bound.
+func NewLeakyBucketStore(capacity float64, leakRate float64) *LeakyBucketStore {
+	return NewLeakyBucketStoreWithLimits(capacity, leakRate, defaultMaxVisitors, defaultVisitorTTL)
+}
+
+// NewLeakyBucketStoreWithLimits is NewLeakyBucketStore with an explicit
+// visitor cap and idle TTL.
+func NewLeakyBucketStoreWithLimits(capacity, leakRate float64, maxVisitors int, ttl time.Duration) *LeakyBucketStore {
+	return &LeakyBucketStore{
+		buckets:  make(map[string]*leakyBucket),
+		capacity: capacity,
+		leakRate: leakRate,
+		maxSize:  maxVisitors,
+		ttl:      ttl,
+	}
+}
+
+// Allow implements Store.
+func (s *LeakyBucketStore) Allow(key string) (Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	b, exists := s.buckets[key]
+	if !exists {
+		if len(s.buckets) >= s.maxSize {
+			s.evictOldestLocked()
+		}
+		b = &leakyBucket{lastLeak: now}
+		s.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.lastLeak).Seconds()
+	b.level = max(0, b.level-elapsed*s.leakRate)
+	b.lastLeak = now
+
+	if b.level+1 > s.capacity {
+		retryAfter := time.Duration((b.level+1-s.capacity)/s.leakRate*1000) * time.Millisecond
+		return Decision{Limit: int(s.capacity), RetryAfter: retryAfter}, nil
+	}
+	b.level++
+	return Decision{Allowed: true, Limit: int(s.capacity), Remaining: int(s.capacity - b.level)}, nil
+}
+
+// Reset implements Store.
+func (s *LeakyBucketStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.buckets, key)
+	return nil
+}
+
+// evictOldestLocked removes the least-recently-used entry (lastLeak already
+// doubles as lastSeen, since every Allow call touches it). The caller must
+// hold s.mu.
+func (s *LeakyBucketStore) evictOldestLocked() {
+	var oldestKey string
+	var oldestAt time.Time
+	for k, b := range s.buckets {
+		if oldestKey == "" || b.lastLeak.Before(oldestAt) {
+			oldestKey, oldestAt = k, b.lastLeak
+		}
+	}
+	if oldestKey != "" {
+		delete(s.buckets, oldestKey)
+	}
+}
+
+// Sweeper periodically evicts entries idle for longer than s.ttl.
+func (s *LeakyBucketStore) Sweeper(interval time.Duration) {
+	t := time.NewTicker(interval)
+	for range t.C {
+		cutoff := time.Now().Add(-s.ttl)
+		s.mu.Lock()
+		for k, b := range s.buckets {
+			if b.lastLeak.Before(cutoff) {
+				delete(s.buckets, k)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+func max(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFixedWindowStoreAllow(t *testing.T) {
+	s := NewFixedWindowStoreWithLimits(2, time.Minute, defaultMaxVisitors, defaultVisitorTTL)
+
+	for i := 0; i < 2; i++ {
+		d, err := s.Allow("a")
+		if err != nil || !d.Allowed {
+			t.Fatalf("request %d: got Decision %+v, err %v, want allowed", i, d, err)
+		}
+	}
+
+	d, err := s.Allow("a")
+	if err != nil || d.Allowed {
+		t.Fatalf("3rd request: got Decision %+v, err %v, want denied", d, err)
+	}
+	if d.RetryAfter <= 0 {
+		t.Errorf("RetryAfter = %v, want > 0 when denied", d.RetryAfter)
+	}
+
+	// A different key has its own budget.
+	if d, err := s.Allow("b"); err != nil || !d.Allowed {
+		t.Fatalf("other key: got Decision %+v, err %v, want allowed", d, err)
+	}
+}
+
+func TestFixedWindowStoreEvictsLeastRecentlyUsedWhenFull(t *testing.T) {
+	s := NewFixedWindowStoreWithLimits(1, time.Minute, 2, time.Hour)
+
+	mustAllow(t, s, "a")
+	mustAllow(t, s, "b")
+	if len(s.counters) != 2 {
+		t.Fatalf("len(counters) = %d, want 2", len(s.counters))
+	}
+
+	// "c" is a third distinct key; the map is capped at 2, so the oldest
+	// entry ("a") must be evicted to make room instead of growing past
+	// the cap.
+	mustAllow(t, s, "c")
+	if len(s.counters) != 2 {
+		t.Fatalf("len(counters) = %d, want 2 (cap enforced)", len(s.counters))
+	}
+	if _, exists := s.counters["a"]; exists {
+		t.Errorf("least-recently-used key %q was not evicted", "a")
+	}
+}
+
+func TestFixedWindowStoreSweeperEvictsIdleEntries(t *testing.T) {
+	s := NewFixedWindowStoreWithLimits(1, time.Minute, defaultMaxVisitors, time.Millisecond)
+	mustAllow(t, s, "a")
+
+	time.Sleep(5 * time.Millisecond)
+	go s.Sweeper(time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	s.mu.Lock()
+	_, exists := s.counters["a"]
+	s.mu.Unlock()
+	if exists {
+		t.Errorf("idle key %q should have been swept", "a")
+	}
+}
+
+func TestSlidingWindowCounterStoreAllow(t *testing.T) {
+	s := NewSlidingWindowCounterStoreWithLimits(2, time.Minute, defaultMaxVisitors, defaultVisitorTTL)
+
+	for i := 0; i < 2; i++ {
+		if d, err := s.Allow("a"); err != nil || !d.Allowed {
+			t.Fatalf("request %d: got Decision %+v, err %v, want allowed", i, d, err)
+		}
+	}
+
+	d, err := s.Allow("a")
+	if err != nil || d.Allowed {
+		t.Fatalf("3rd request: got Decision %+v, err %v, want denied", d, err)
+	}
+}
+
+func TestSlidingWindowLogStoreAllow(t *testing.T) {
+	s := NewSlidingWindowLogStoreWithLimits(2, time.Minute, defaultMaxVisitors, defaultVisitorTTL)
+
+	for i := 0; i < 2; i++ {
+		if d, err := s.Allow("a"); err != nil || !d.Allowed {
+			t.Fatalf("request %d: got Decision %+v, err %v, want allowed", i, d, err)
+		}
+	}
+
+	if d, err := s.Allow("a"); err != nil || d.Allowed {
+		t.Fatalf("3rd request: got Decision %+v, err %v, want denied", d, err)
+	}
+
+	if err := s.Reset("a"); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if d, err := s.Allow("a"); err != nil || !d.Allowed {
+		t.Fatalf("after reset: got Decision %+v, err %v, want allowed", d, err)
+	}
+}
+
+func TestLeakyBucketStoreAllow(t *testing.T) {
+	s := NewLeakyBucketStoreWithLimits(1, 1, defaultMaxVisitors, defaultVisitorTTL)
+
+	if d, err := s.Allow("a"); err != nil || !d.Allowed {
+		t.Fatalf("1st request: got Decision %+v, err %v, want allowed", d, err)
+	}
+
+	d, err := s.Allow("a")
+	if err != nil || d.Allowed {
+		t.Fatalf("2nd request: got Decision %+v, err %v, want denied (bucket full)", d, err)
+	}
+	if d.RetryAfter <= 0 {
+		t.Errorf("RetryAfter = %v, want > 0 when denied", d.RetryAfter)
+	}
+}
+
+func TestLeakyBucketStoreEvictsLeastRecentlyUsedWhenFull(t *testing.T) {
+	s := NewLeakyBucketStoreWithLimits(5, 1, 2, time.Hour)
+
+	mustAllow(t, s, "a")
+	mustAllow(t, s, "b")
+	mustAllow(t, s, "c")
+
+	if len(s.buckets) != 2 {
+		t.Fatalf("len(buckets) = %d, want 2 (cap enforced)", len(s.buckets))
+	}
+	if _, exists := s.buckets["a"]; exists {
+		t.Errorf("least-recently-used key %q was not evicted", "a")
+	}
+}
+
+// mustAllow calls Allow and fails the test if it errors.
+func mustAllow(t *testing.T, s Store, key string) Decision {
+	t.Helper()
+	d, err := s.Allow(key)
+	if err != nil {
+		t.Fatalf("Allow(%q): %v", key, err)
+	}
+	return d
+}
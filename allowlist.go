@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"expvar"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+const defaultProxyKeyHeader = "X-Redash-Proxy-Key"
+
+// allowlistUsage exposes per-key bypass counts at /debug/vars for
+// observability, e.g. to notice a batch job hammering Redash harder than
+// expected. Labels are hashed (see hashAllowlistKey) so the metric can't leak
+// the pre-shared secrets it's counting. /debug/vars is only reachable if the
+// process serves http.DefaultServeMux, which main.go deliberately avoids.
+var allowlistUsage = expvar.NewMap("redash_proxy_allowlist_usage")
+
+// hashAllowlistKey returns an opaque label for key, short enough to be a
+// useful expvar field name without exposing the pre-shared secret itself.
+func hashAllowlistKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// Allowlist holds a set of pre-shared keys that, when presented in a
+// configurable header, bypass LimitMiddleware entirely. This lets internal
+// batch jobs and dashboards that legitimately need to hit Redash frequently
+// avoid being throttled alongside human, cookie-based traffic.
+type Allowlist struct {
+	header string
+	keys   map[string]*uint64
+}
+
+// NewAllowlist builds an Allowlist that bypasses rate limiting for requests
+// whose header value matches one of keys. An empty header falls back to
+// defaultProxyKeyHeader.
+func NewAllowlist(header string, keys []string) *Allowlist {
+	if header == "" {
+		header = defaultProxyKeyHeader
+	}
+
+	al := &Allowlist{header: header, keys: make(map[string]*uint64, len(keys))}
+	for _, k := range keys {
+		if k == "" {
+			continue
+		}
+		var n uint64
+		al.keys[k] = &n
+	}
+	return al
+}
+
+// Allows reports whether r carries a pre-shared key that bypasses rate
+// limiting, bumping that key's usage counter if so.
+func (al *Allowlist) Allows(r *http.Request) bool {
+	if al == nil || len(al.keys) == 0 {
+		return false
+	}
+
+	key := r.Header.Get(al.header)
+	counter, ok := al.keys[key]
+	if !ok {
+		return false
+	}
+
+	atomic.AddUint64(counter, 1)
+	allowlistUsage.Add(hashAllowlistKey(key), 1)
+	return true
+}
+
+// loadAllowlistKeys reads pre-shared keys from a config file (one key per
+// line, blank lines and "#" comments ignored) at path. When path is empty it
+// falls back to the comma-separated PROXY_KEYS env var.
+func loadAllowlistKeys(path string) ([]string, error) {
+	if path == "" {
+		env := os.Getenv("PROXY_KEYS")
+		if env == "" {
+			return nil, nil
+		}
+		parts := strings.Split(env, ",")
+		keys := make([]string, len(parts))
+		for i, p := range parts {
+			keys[i] = strings.TrimSpace(p)
+		}
+		return keys, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keys = append(keys, line)
+	}
+	return keys, nil
+}
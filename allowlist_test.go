@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAllowlistAllows(t *testing.T) {
+	al := NewAllowlist("", []string{"key1", "key2"})
+
+	r := newTestRequest(t, defaultProxyKeyHeader, "key1")
+	if !al.Allows(r) {
+		t.Error("Allows() = false, want true for a configured key")
+	}
+
+	r = newTestRequest(t, defaultProxyKeyHeader, "nope")
+	if al.Allows(r) {
+		t.Error("Allows() = true, want false for an unconfigured key")
+	}
+
+	r = newTestRequest(t, defaultProxyKeyHeader, "")
+	if al.Allows(r) {
+		t.Error("Allows() = true, want false for a missing header")
+	}
+}
+
+func TestAllowlistCustomHeader(t *testing.T) {
+	al := NewAllowlist("X-Custom-Key", []string{"key1"})
+
+	r := newTestRequest(t, "X-Custom-Key", "key1")
+	if !al.Allows(r) {
+		t.Error("Allows() = false, want true via the configured header name")
+	}
+
+	r = newTestRequest(t, defaultProxyKeyHeader, "key1")
+	if al.Allows(r) {
+		t.Error("Allows() = true, want false when the key arrives on the default header instead of the configured one")
+	}
+}
+
+func TestAllowlistNilIsSafe(t *testing.T) {
+	var al *Allowlist
+	if al.Allows(newTestRequest(t, defaultProxyKeyHeader, "key1")) {
+		t.Error("Allows() on a nil Allowlist = true, want false")
+	}
+}
+
+func newTestRequest(t *testing.T, header, value string) *http.Request {
+	t.Helper()
+	r, err := http.NewRequest(http.MethodGet, "/", nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	if value != "" {
+		r.Header.Set(header, value)
+	}
+	return r
+}
+
+func TestLoadAllowlistKeysFromEnv(t *testing.T) {
+	t.Setenv("PROXY_KEYS", "key1, key2 ,key3")
+
+	keys, err := loadAllowlistKeys("")
+	if err != nil {
+		t.Fatalf("loadAllowlistKeys: %v", err)
+	}
+
+	want := []string{"key1", "key2", "key3"}
+	if len(keys) != len(want) {
+		t.Fatalf("keys = %q, want %q", keys, want)
+	}
+	for i, k := range keys {
+		if k != want[i] {
+			t.Errorf("keys[%d] = %q, want %q", i, k, want[i])
+		}
+	}
+}
+
+func TestLoadAllowlistKeysFromFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "keys.txt")
+	content := "key1\n# a comment\n\n  key2  \n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	keys, err := loadAllowlistKeys(path)
+	if err != nil {
+		t.Fatalf("loadAllowlistKeys: %v", err)
+	}
+
+	want := []string{"key1", "key2"}
+	if len(keys) != len(want) {
+		t.Fatalf("keys = %q, want %q", keys, want)
+	}
+	for i, k := range keys {
+		if k != want[i] {
+			t.Errorf("keys[%d] = %q, want %q", i, k, want[i])
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+
+	"golang.org/x/time/rate"
+)
+
+// unshardedMemoryStore is the original single-mutex MemoryStore, kept here
+// only as a baseline to benchmark the sharded implementation against.
+type unshardedMemoryStore struct {
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+	rate     rate.Limit
+	burst    int
+}
+
+func newUnshardedMemoryStore(rl rate.Limit, burst int) *unshardedMemoryStore {
+	return &unshardedMemoryStore{
+		limiters: make(map[string]*rate.Limiter),
+		rate:     rl,
+		burst:    burst,
+	}
+}
+
+func (s *unshardedMemoryStore) getLimiter(key string) *rate.Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if lim, exists := s.limiters[key]; exists {
+		return lim
+	}
+
+	lim := rate.NewLimiter(s.rate, s.burst)
+	s.limiters[key] = lim
+	return lim
+}
+
+func benchmarkTokens(n int) []string {
+	tokens := make([]string, n)
+	for i := range tokens {
+		tokens[i] = "token-" + strconv.Itoa(i)
+	}
+	return tokens
+}
+
+func BenchmarkUnshardedMemoryStore(b *testing.B) {
+	for _, n := range []int{1000, 10000} {
+		b.Run(fmt.Sprintf("%d-tokens", n), func(b *testing.B) {
+			store := newUnshardedMemoryStore(rate.Every(0), n)
+			tokens := benchmarkTokens(n)
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					store.getLimiter(tokens[i%n]).Allow()
+					i++
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkShardedMemoryStore(b *testing.B) {
+	for _, n := range []int{1000, 10000} {
+		b.Run(fmt.Sprintf("%d-tokens", n), func(b *testing.B) {
+			store := NewMemoryStore(rate.Every(0), n)
+			tokens := benchmarkTokens(n)
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					store.getLimiter(tokens[i%n]).Allow()
+					i++
+				}
+			})
+		})
+	}
+}
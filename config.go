@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// IdentifierStrategy selects how a Rule extracts the key used to look up a
+// rate limiter for an incoming request.
+type IdentifierStrategy string
+
+const (
+	IdentifierCookie IdentifierStrategy = "cookie"
+	IdentifierHeader IdentifierStrategy = "header"
+	IdentifierQuery  IdentifierStrategy = "query"
+	IdentifierIP     IdentifierStrategy = "ip"
+)
+
+// BypassOp selects how a BypassRule compares a request body field against
+// its configured Value.
+type BypassOp string
+
+const (
+	// BypassEquals bypasses rate limiting when the field equals Value.
+	BypassEquals BypassOp = "eq"
+	// BypassNotEquals bypasses rate limiting when the field does not equal
+	// Value (or is absent, since an absent field behaves like its zero
+	// value once decoded).
+	BypassNotEquals BypassOp = "neq"
+)
+
+// BypassRule lets a Rule declare "skip rate limiting if request body field
+// Field equals (or doesn't equal) Value", instead of that condition being
+// hard-coded in LimitMiddleware. Op defaults to BypassEquals.
+type BypassRule struct {
+	Field string   `yaml:"field" json:"field"`
+	Value any      `yaml:"value" json:"value"`
+	Op    BypassOp `yaml:"op" json:"op"`
+}
+
+// Rule configures one rate limit: which requests it covers, how to identify
+// the caller, and what budget/algorithm to enforce against them.
+type Rule struct {
+	// Method is matched exactly against the request method; empty matches
+	// any method.
+	Method string `yaml:"method" json:"method"`
+	// Pattern is matched against the request path with path.Match, so "*"
+	// matches any run of non-separator characters.
+	Pattern string `yaml:"pattern" json:"pattern"`
+
+	// Algorithm selects the Store implementation: "token_bucket" (default),
+	// "fixed_window", "sliding_window_log", "sliding_window_counter", or
+	// "leaky_bucket".
+	Algorithm string `yaml:"algorithm" json:"algorithm"`
+	// Rate is the refill/leak rate in requests per second.
+	Rate float64 `yaml:"rate" json:"rate"`
+	// Burst is the bucket capacity (token bucket, leaky bucket) or the
+	// request count allowed per Window (fixed/sliding window algorithms).
+	Burst int `yaml:"burst" json:"burst"`
+	// Window is the window size for fixed/sliding window algorithms,
+	// parsed with time.ParseDuration. Defaults to 1 minute.
+	Window string `yaml:"window" json:"window"`
+
+	// Identifier selects how the caller is identified; defaults to
+	// IdentifierCookie.
+	Identifier IdentifierStrategy `yaml:"identifier" json:"identifier"`
+	// IdentifierName is the cookie/header/query param name to read,
+	// defaulting to "remember_token" for IdentifierCookie.
+	IdentifierName string `yaml:"identifier_name" json:"identifier_name"`
+
+	// Bypass, when set, skips rate limiting entirely for requests whose
+	// body matches it.
+	Bypass *BypassRule `yaml:"bypass" json:"bypass"`
+
+	// ResponseMode selects how a denied request is reported: "standard"
+	// (default) sends a 429 with Retry-After and RateLimit-* headers per
+	// draft-ietf-httpapi-ratelimit-headers, while "legacy" preserves the
+	// original behavior of a 200 carrying the Redash job-status error
+	// payload, which the Redash UI's job-status polling expects.
+	ResponseMode string `yaml:"response_mode" json:"response_mode"`
+}
+
+const (
+	ResponseModeStandard = "standard"
+	ResponseModeLegacy   = "legacy"
+)
+
+// window returns r.Window parsed as a duration, defaulting to 1 minute.
+func (r Rule) window() (time.Duration, error) {
+	if r.Window == "" {
+		return time.Minute, nil
+	}
+	return time.ParseDuration(r.Window)
+}
+
+// Config is the top-level rate limit configuration: an ordered list of
+// rules tried in turn, plus a Default applied when none match.
+type Config struct {
+	Rules   []Rule `yaml:"rules" json:"rules"`
+	Default Rule   `yaml:"default" json:"default"`
+}
+
+// legacyMaxAgeBypass reproduces the proxy's original hard-coded behavior of
+// letting a request through whenever the query payload's max_age field was
+// non-zero, regardless of the limiter's decision.
+var legacyMaxAgeBypass = &BypassRule{Field: "max_age", Value: float64(0), Op: BypassNotEquals}
+
+// defaultConfig mirrors the proxy's original hard-coded behavior, used when
+// no RULES_FILE is configured: a single token bucket of 15 requests/minute
+// keyed by the remember_token cookie, with sliding-window submission
+// limiting and token-bucket result polling on their respective endpoints,
+// each still honoring the legacy max_age bypass.
+func defaultConfig() *Config {
+	return &Config{
+		Rules: []Rule{
+			{Pattern: "/api/queries/*/results", Algorithm: "token_bucket", Rate: 1.0 / 60, Burst: 15, ResponseMode: ResponseModeLegacy, Bypass: legacyMaxAgeBypass},
+			{Pattern: "/api/queries", Algorithm: "sliding_window_counter", Burst: 15, Window: "1m", ResponseMode: ResponseModeStandard, Bypass: legacyMaxAgeBypass},
+		},
+		Default: Rule{Algorithm: "token_bucket", Rate: 1.0 / 60, Burst: 15, ResponseMode: ResponseModeStandard, Bypass: legacyMaxAgeBypass},
+	}
+}
+
+// LoadConfig reads a rate limit Config from a YAML or JSON file at path,
+// choosing the decoder by extension (".json", otherwise YAML).
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	var cfg Config
+	if strings.HasSuffix(path, ".json") {
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+	} else {
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+	}
+	return &cfg, nil
+}
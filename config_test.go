@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRuleWindowDefault(t *testing.T) {
+	r := Rule{}
+	d, err := r.window()
+	if err != nil {
+		t.Fatalf("window: %v", err)
+	}
+	if d != time.Minute {
+		t.Errorf("window = %v, want %v", d, time.Minute)
+	}
+}
+
+func TestRuleWindowParses(t *testing.T) {
+	r := Rule{Window: "30s"}
+	d, err := r.window()
+	if err != nil {
+		t.Fatalf("window: %v", err)
+	}
+	if d != 30*time.Second {
+		t.Errorf("window = %v, want %v", d, 30*time.Second)
+	}
+}
+
+func TestLoadConfigYAML(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rules.yaml")
+	content := `
+rules:
+  - pattern: /api/queries
+    algorithm: fixed_window
+    burst: 10
+    window: 1m
+default:
+  algorithm: token_bucket
+  rate: 0.5
+  burst: 5
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	if len(cfg.Rules) != 1 {
+		t.Fatalf("len(Rules) = %d, want 1", len(cfg.Rules))
+	}
+	if cfg.Rules[0].Pattern != "/api/queries" || cfg.Rules[0].Algorithm != "fixed_window" || cfg.Rules[0].Burst != 10 {
+		t.Errorf("Rules[0] = %+v, unexpected", cfg.Rules[0])
+	}
+	if cfg.Default.Rate != 0.5 || cfg.Default.Burst != 5 {
+		t.Errorf("Default = %+v, unexpected", cfg.Default)
+	}
+}
+
+func TestLoadConfigJSON(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rules.json")
+	content := `{
+		"rules": [{"pattern": "/api/queries", "algorithm": "fixed_window", "burst": 10, "window": "1m"}],
+		"default": {"algorithm": "token_bucket", "rate": 0.5, "burst": 5}
+	}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	if len(cfg.Rules) != 1 || cfg.Rules[0].Burst != 10 {
+		t.Errorf("Rules = %+v, unexpected", cfg.Rules)
+	}
+	if cfg.Default.Rate != 0.5 {
+		t.Errorf("Default = %+v, unexpected", cfg.Default)
+	}
+}
+
+func TestDefaultConfigRulesBypassLegacyMaxAge(t *testing.T) {
+	cfg := defaultConfig()
+	for _, r := range cfg.Rules {
+		if r.Bypass != legacyMaxAgeBypass {
+			t.Errorf("rule %q: Bypass = %v, want legacyMaxAgeBypass", r.Pattern, r.Bypass)
+		}
+	}
+	if cfg.Default.Bypass != legacyMaxAgeBypass {
+		t.Errorf("Default.Bypass = %v, want legacyMaxAgeBypass", cfg.Default.Bypass)
+	}
+}
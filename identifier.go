@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// defaultIdentifierName is used for IdentifierCookie when a Rule doesn't
+// override it.
+const defaultIdentifierName = "remember_token"
+
+// extractIdentifier returns the key used to look up r's rate limiter under
+// rule, and whether one could be extracted at all.
+func extractIdentifier(r *http.Request, rule Rule) (string, bool) {
+	switch rule.Identifier {
+	case IdentifierHeader:
+		v := r.Header.Get(rule.IdentifierName)
+		return v, v != ""
+	case IdentifierQuery:
+		v := r.URL.Query().Get(rule.IdentifierName)
+		return v, v != ""
+	case IdentifierIP:
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		return host, host != ""
+	case IdentifierCookie, "":
+		name := rule.IdentifierName
+		if name == "" {
+			name = defaultIdentifierName
+		}
+		c, err := r.Cookie(name)
+		if err != nil {
+			return "", false
+		}
+		return c.Value, true
+	default:
+		return "", false
+	}
+}
+
+// bypasses reports whether the decoded JSON request body matches rule's
+// Bypass condition, meaning the request should skip rate limiting entirely.
+func bypasses(body map[string]any, rule Rule) bool {
+	if rule.Bypass == nil {
+		return false
+	}
+
+	// A field missing from the body behaves like it decoded to its zero
+	// value, matching how the original typed-struct decode treated an
+	// absent max_age as 0.
+	v, ok := body[rule.Bypass.Field]
+	if !ok {
+		v = float64(0)
+	}
+
+	equals := fmt.Sprint(v) == fmt.Sprint(rule.Bypass.Value)
+	if rule.Bypass.Op == BypassNotEquals {
+		return !equals
+	}
+	return equals
+}
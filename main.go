@@ -8,9 +8,10 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"net/url"
-	"sync"
+	"os"
 	"time"
 
+	"github.com/redis/go-redis/v9"
 	"golang.org/x/time/rate"
 )
 
@@ -21,87 +22,103 @@ const RLPayload = `{
     }
 }`
 
-// RateLimiters holds a map of rate limiters associated with cookie tokens and a mutex for safe concurrent access
-type RateLimiters struct {
-	mu       sync.Mutex
-	limiters map[string]*rate.Limiter
-	rate     rate.Limit
-	burst    int
-}
-
-// NewRateLimiters creates a new RateLimiters instance
-func NewRateLimiters(rl rate.Limit, burst int) *RateLimiters {
-	return &RateLimiters{
-		limiters: make(map[string]*rate.Limiter),
-		rate:     rl,
-		burst:    burst,
-	}
-}
-
-// Flusher flushes the rate limiters every 2 hours
-func (rl *RateLimiters) Flusher(duration time.Duration) {
-	t := time.NewTicker(duration)
-	for {
-		select {
-		case <-t.C:
-			rl.mu.Lock()
-			rl.limiters = make(map[string]*rate.Limiter)
-			rl.mu.Unlock()
-		}
-	}
-}
-
-// GetLimiter returns the rate limiter for the given token, creating a new one if necessary
-func (rl *RateLimiters) GetLimiter(token string) *rate.Limiter {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	if _, exists := rl.limiters[token]; exists {
-		return rl.limiters[token]
-	}
-
-	lim := rate.NewLimiter(rl.rate, rl.burst)
-	rl.limiters[token] = lim
-	return lim
-}
-
-// LimitMiddleware is a middleware that rate limits based on a "remember_token" cookie
-func LimitMiddleware(limiters *RateLimiters) func(http.Handler) http.Handler {
+// LimitMiddleware is a middleware that rate limits requests according to
+// router's rules, identifying callers per-rule (cookie, header, query param,
+// or IP) and honoring each rule's declarative bypass condition. Requests
+// carrying a key from allowlist bypass rate limiting entirely.
+func LimitMiddleware(router *Router, allowlist *Allowlist) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			remToken, err := r.Cookie("remember_token")
-			if err != nil {
-				w.Write([]byte("No cookie found"))
+			if allowlist.Allows(r) {
+				next.ServeHTTP(w, r)
 				return
 			}
 
-			var b bytes.Buffer
+			ruleStore := router.ruleFor(r)
 
-			r.Body = io.NopCloser(io.TeeReader(r.Body, &b))
-			defer r.Body.Close()
+			if ruleStore.Rule.Bypass != nil {
+				var b bytes.Buffer
+				r.Body = io.NopCloser(io.TeeReader(r.Body, &b))
 
-			queryPayload := struct {
-				MaxAge int64 `json:"max_age"`
-			}{}
+				body := map[string]any{}
+				err := json.NewDecoder(r.Body).Decode(&body)
+				r.Body = io.NopCloser(&b)
 
-			if err = json.NewDecoder(r.Body).Decode(&queryPayload); err != nil {
-				w.Write([]byte("Error parsing request body" + err.Error()))
+				// A body that isn't valid JSON (file uploads, form posts,
+				// etc.) simply can't match a Bypass condition; fall through
+				// to normal rate limiting rather than rejecting the request.
+				if (err == nil || err == io.EOF) && bypasses(body, ruleStore.Rule) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			key, ok := extractIdentifier(r, ruleStore.Rule)
+			if !ok {
+				w.Write([]byte("No identifier found"))
 				return
 			}
 
-			if !limiters.GetLimiter(remToken.Value).Allow() && queryPayload.MaxAge == 0 {
-				w.Header().Set("Content-Type", "application/json")
-				w.Write([]byte(RLPayload))
+			decision, err := ruleStore.Store.Allow(key)
+			if err != nil {
+				log.Printf("rate limit store error: %v", err)
+				decision = Decision{Allowed: true}
+			}
+
+			if !decision.Allowed {
+				writeLimited(w, ruleStore.Rule, decision)
 				return
 			}
 
-			r.Body = io.NopCloser(&b)
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
+// visitorSweepInterval is how often MemoryStore checks for idle visitors to
+// evict. It's short because each sweep only holds a shard's lock long
+// enough to delete the keys it already found expired.
+const visitorSweepInterval = 30 * time.Second
+
+// newTokenBucketStore builds the configured token-bucket Store from the
+// STORE env var, defaulting to the in-process memory store when unset.
+func newTokenBucketStore(rl rate.Limit, burst int) Store {
+	switch os.Getenv("STORE") {
+	case "redis":
+		addr := os.Getenv("REDIS_ADDR")
+		if addr == "" {
+			addr = "127.0.0.1:6379"
+		}
+		prefix := os.Getenv("REDIS_PREFIX")
+		if prefix == "" {
+			prefix = "redash-rate-limit-proxy:"
+		}
+		client := redis.NewClient(&redis.Options{Addr: addr})
+		return NewRedisStore(client, prefix, float64(rl), burst)
+	default:
+		ttl := defaultVisitorTTL
+		if v := os.Getenv("VISITOR_TTL"); v != "" {
+			if d, err := time.ParseDuration(v); err == nil {
+				ttl = d
+			}
+		}
+		store := NewMemoryStoreWithLimits(rl, burst, defaultMaxVisitors, ttl)
+		go store.Sweeper(visitorSweepInterval)
+		return store
+	}
+}
+
+// loadConfig reads the rate limit Config from RULES_FILE if set, otherwise
+// falls back to defaultConfig so the proxy still runs with sane defaults.
+func loadConfig() (*Config, error) {
+	path := os.Getenv("RULES_FILE")
+	if path == "" {
+		return defaultConfig(), nil
+	}
+	return LoadConfig(path)
+}
+
 func main() {
 	target, err := url.Parse("http://127.0.0.1:80")
 	if err != nil {
@@ -109,10 +126,26 @@ func main() {
 	}
 
 	proxy := httputil.NewSingleHostReverseProxy(target)
-	rateLimiters := NewRateLimiters(rate.Every(time.Minute), 15)
 
-	go rateLimiters.Flusher(time.Minute * 120)
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	router, err := NewRouter(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	http.Handle("/", LimitMiddleware(rateLimiters)(proxy))
-	log.Fatal(http.ListenAndServe(":3080", nil))
+	keys, err := loadAllowlistKeys(os.Getenv("PROXY_KEYS_FILE"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	allowlist := NewAllowlist(os.Getenv("PROXY_KEY_HEADER"), keys)
+
+	// A dedicated mux, rather than http.DefaultServeMux, keeps anything an
+	// imported package registers on the default mux (e.g. expvar's
+	// /debug/vars) off this public-facing listener.
+	mux := http.NewServeMux()
+	mux.Handle("/", LimitMiddleware(router, allowlist)(proxy))
+	log.Fatal(http.ListenAndServe(":3080", mux))
 }
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript atomically refills and debits a token bucket stored as a
+// Redis hash with "tokens" and "last_refill" fields. KEYS[1] is the bucket
+// key; ARGV is rate (tokens/sec), burst, the current unix time (seconds,
+// float), and the key TTL (seconds). It returns {allowed (0/1), tokens
+// remaining, milliseconds until a token would next be available}.
+const tokenBucketScript = `
+local key = KEYS[1]
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+
+local bucket = redis.call("HMGET", key, "tokens", "last_refill")
+local tokens = tonumber(bucket[1])
+local lastRefill = tonumber(bucket[2])
+
+if tokens == nil then
+    tokens = burst
+    lastRefill = now
+end
+
+local elapsed = math.max(0, now - lastRefill)
+tokens = math.min(burst, tokens + elapsed * rate)
+
+local allowed = 0
+local retryMs = 0
+if tokens >= 1 then
+    allowed = 1
+    tokens = tokens - 1
+else
+    retryMs = math.ceil((1 - tokens) / rate * 1000)
+end
+
+redis.call("HMSET", key, "tokens", tokens, "last_refill", now)
+redis.call("EXPIRE", key, ttl)
+
+return {allowed, math.floor(tokens), retryMs}
+`
+
+// RedisStore is a Store backed by Redis, so that multiple proxy instances
+// behind a load balancer share the same limit state instead of each
+// replica keeping its own map. The refill/debit is done in a single Lua
+// script so concurrent requests for the same key can't race each other.
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+	rate   float64 // tokens per second
+	burst  int
+	script *redis.Script
+	ttl    time.Duration
+}
+
+// NewRedisStore creates a Store backed by the given Redis client. rl is the
+// refill rate in tokens per second and burst is the bucket capacity. Keys
+// are stored with the given prefix so multiple proxies (or algorithms) can
+// share a Redis instance without colliding.
+func NewRedisStore(client *redis.Client, prefix string, rl float64, burst int) *RedisStore {
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+		rate:   rl,
+		burst:  burst,
+		script: redis.NewScript(tokenBucketScript),
+		// TTL must outlive a full refill so an idle bucket doesn't get
+		// reaped mid-window, but shouldn't linger much longer than that.
+		ttl: time.Duration(float64(burst)/rl*1.2*float64(time.Second)) + time.Second,
+	}
+}
+
+// Allow implements Store.
+func (s *RedisStore) Allow(key string) (Decision, error) {
+	ctx := context.Background()
+	now := float64(time.Now().UnixNano()) / float64(time.Second)
+
+	res, err := s.script.Run(ctx, s.client, []string{s.prefix + key}, s.rate, s.burst, now, int(s.ttl.Seconds())).Slice()
+	if err != nil {
+		return Decision{}, err
+	}
+
+	allowed, _ := res[0].(int64)
+	remaining, _ := res[1].(int64)
+	retryMs, _ := res[2].(int64)
+
+	return Decision{
+		Allowed:    allowed == 1,
+		Limit:      s.burst,
+		Remaining:  int(remaining),
+		RetryAfter: time.Duration(retryMs) * time.Millisecond,
+	}, nil
+}
+
+// Reset implements Store.
+func (s *RedisStore) Reset(key string) error {
+	return s.client.Del(context.Background(), s.prefix+key).Err()
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestRedisStore spins up an in-process miniredis server and returns a
+// RedisStore backed by it, so the Lua token-bucket script can be exercised
+// without a real Redis instance.
+func newTestRedisStore(t *testing.T, rate float64, burst int) *RedisStore {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return NewRedisStore(client, "test:", rate, burst)
+}
+
+func TestRedisStoreAllow(t *testing.T) {
+	s := newTestRedisStore(t, 1, 2)
+
+	for i := 0; i < 2; i++ {
+		d, err := s.Allow("a")
+		if err != nil || !d.Allowed {
+			t.Fatalf("request %d: got Decision %+v, err %v, want allowed", i, d, err)
+		}
+	}
+
+	d, err := s.Allow("a")
+	if err != nil || d.Allowed {
+		t.Fatalf("3rd request: got Decision %+v, err %v, want denied", d, err)
+	}
+	if d.RetryAfter <= 0 {
+		t.Errorf("RetryAfter = %v, want > 0 when denied", d.RetryAfter)
+	}
+
+	// A different key has its own bucket.
+	if d, err := s.Allow("b"); err != nil || !d.Allowed {
+		t.Fatalf("other key: got Decision %+v, err %v, want allowed", d, err)
+	}
+}
+
+func TestRedisStoreReset(t *testing.T) {
+	s := newTestRedisStore(t, 1, 1)
+
+	mustAllow(t, s, "a")
+	if d, err := s.Allow("a"); err != nil || d.Allowed {
+		t.Fatalf("2nd request before reset: got Decision %+v, err %v, want denied", d, err)
+	}
+
+	if err := s.Reset("a"); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if d, err := s.Allow("a"); err != nil || !d.Allowed {
+		t.Fatalf("after reset: got Decision %+v, err %v, want allowed", d, err)
+	}
+}
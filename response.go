@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// writeLimited writes the "request denied" response for rule, in the mode
+// rule.ResponseMode selects.
+func writeLimited(w http.ResponseWriter, rule Rule, decision Decision) {
+	if rule.ResponseMode == ResponseModeLegacy {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(RLPayload))
+		return
+	}
+
+	retryAfterSeconds := int(decision.RetryAfter.Round(time.Second).Seconds())
+	if retryAfterSeconds < 1 {
+		retryAfterSeconds = 1
+	}
+
+	w.Header().Set("Retry-After", fmt.Sprintf("%d", retryAfterSeconds))
+	w.Header().Set("RateLimit-Limit", fmt.Sprintf("%d", decision.Limit))
+	w.Header().Set("RateLimit-Remaining", "0")
+	w.Header().Set("RateLimit-Reset", fmt.Sprintf("%d", retryAfterSeconds))
+	w.WriteHeader(http.StatusTooManyRequests)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWriteLimitedStandard(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeLimited(w, Rule{ResponseMode: ResponseModeStandard}, Decision{Limit: 15, RetryAfter: 30 * time.Second})
+
+	if w.Code != 429 {
+		t.Errorf("status = %d, want 429", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After = %q, want %q", got, "30")
+	}
+	if got := w.Header().Get("RateLimit-Limit"); got != "15" {
+		t.Errorf("RateLimit-Limit = %q, want %q", got, "15")
+	}
+	if got := w.Header().Get("RateLimit-Remaining"); got != "0" {
+		t.Errorf("RateLimit-Remaining = %q, want %q", got, "0")
+	}
+	if got := w.Header().Get("RateLimit-Reset"); got != "30" {
+		t.Errorf("RateLimit-Reset = %q, want %q", got, "30")
+	}
+}
+
+func TestWriteLimitedStandardRoundsUpSubSecondRetry(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeLimited(w, Rule{ResponseMode: ResponseModeStandard}, Decision{RetryAfter: 200 * time.Millisecond})
+
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want %q (rounded up to at least 1s)", got, "1")
+	}
+}
+
+func TestWriteLimitedLegacy(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeLimited(w, Rule{ResponseMode: ResponseModeLegacy}, Decision{})
+
+	if w.Code != 200 {
+		t.Errorf("status = %d, want 200 (legacy mode keeps the original 200 status)", w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want %q", got, "application/json")
+	}
+	if w.Body.String() != RLPayload {
+		t.Errorf("body = %q, want %q", w.Body.String(), RLPayload)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+
+	"golang.org/x/time/rate"
+)
+
+// RuleStore pairs a configured Rule with the Store built for it.
+type RuleStore struct {
+	Rule  Rule
+	Store Store
+}
+
+// Router picks the RuleStore to apply to a request, trying rules in order
+// and falling back to the default rule when none match.
+type Router struct {
+	Rules   []RuleStore
+	Default RuleStore
+}
+
+// ruleFor returns the first RuleStore whose method and pattern match r, or
+// the default RuleStore if none do.
+func (router *Router) ruleFor(r *http.Request) RuleStore {
+	for _, rs := range router.Rules {
+		if rs.Rule.Method != "" && rs.Rule.Method != r.Method {
+			continue
+		}
+		if matched, err := path.Match(rs.Rule.Pattern, r.URL.Path); err == nil && matched {
+			return rs
+		}
+	}
+	return router.Default
+}
+
+// NewRouter builds a Router from cfg, constructing the Store each rule
+// needs.
+func NewRouter(cfg *Config) (*Router, error) {
+	router := &Router{Rules: make([]RuleStore, 0, len(cfg.Rules))}
+
+	for _, rule := range cfg.Rules {
+		store, err := buildStore(rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q %q: %w", rule.Method, rule.Pattern, err)
+		}
+		router.Rules = append(router.Rules, RuleStore{Rule: rule, Store: store})
+	}
+
+	defaultStore, err := buildStore(cfg.Default)
+	if err != nil {
+		return nil, fmt.Errorf("default rule: %w", err)
+	}
+	router.Default = RuleStore{Rule: cfg.Default, Store: defaultStore}
+
+	return router, nil
+}
+
+// buildStore constructs the Store implementation for rule.Algorithm.
+func buildStore(rule Rule) (Store, error) {
+	window, err := rule.window()
+	if err != nil {
+		return nil, fmt.Errorf("invalid window %q: %w", rule.Window, err)
+	}
+
+	switch rule.Algorithm {
+	case "", "token_bucket":
+		if rule.Rate <= 0 {
+			return nil, fmt.Errorf("algorithm %q requires rate > 0, got %v", rule.Algorithm, rule.Rate)
+		}
+		return newTokenBucketStore(rate.Limit(rule.Rate), rule.Burst), nil
+	case "fixed_window":
+		store := NewFixedWindowStore(rule.Burst, window)
+		go store.Sweeper(visitorSweepInterval)
+		return store, nil
+	case "sliding_window_counter":
+		store := NewSlidingWindowCounterStore(rule.Burst, window)
+		go store.Sweeper(visitorSweepInterval)
+		return store, nil
+	case "sliding_window_log":
+		store := NewSlidingWindowLogStore(rule.Burst, window)
+		go store.Sweeper(visitorSweepInterval)
+		return store, nil
+	case "leaky_bucket":
+		if rule.Rate <= 0 {
+			return nil, fmt.Errorf("algorithm %q requires rate > 0, got %v", rule.Algorithm, rule.Rate)
+		}
+		store := NewLeakyBucketStore(float64(rule.Burst), rule.Rate)
+		go store.Sweeper(visitorSweepInterval)
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown algorithm %q", rule.Algorithm)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouterMatchesPatternAndMethod(t *testing.T) {
+	cfg := &Config{
+		Rules: []Rule{
+			{Method: http.MethodPost, Pattern: "/api/queries", Algorithm: "fixed_window", Rate: 1, Burst: 5},
+		},
+		Default: Rule{Algorithm: "token_bucket", Rate: 1, Burst: 5},
+	}
+	router, err := NewRouter(cfg)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/api/queries", nil)
+	if rs := router.ruleFor(r); rs.Rule.Pattern != "/api/queries" {
+		t.Errorf("ruleFor matched %+v, want the /api/queries rule", rs.Rule)
+	}
+
+	// Wrong method falls through to Default.
+	r = httptest.NewRequest(http.MethodGet, "/api/queries", nil)
+	if rs := router.ruleFor(r); rs.Rule.Pattern != "" {
+		t.Errorf("ruleFor matched %+v, want Default", rs.Rule)
+	}
+
+	// Unmatched path falls through to Default.
+	r = httptest.NewRequest(http.MethodPost, "/other", nil)
+	if rs := router.ruleFor(r); rs.Rule.Pattern != "" {
+		t.Errorf("ruleFor matched %+v, want Default", rs.Rule)
+	}
+}
+
+func TestBuildStoreRejectsZeroRateTokenBucket(t *testing.T) {
+	_, err := buildStore(Rule{Algorithm: "token_bucket", Burst: 15})
+	if err == nil {
+		t.Fatal("buildStore with rate 0 = nil error, want an error")
+	}
+}
+
+func TestBuildStoreRejectsZeroRateLeakyBucket(t *testing.T) {
+	_, err := buildStore(Rule{Algorithm: "leaky_bucket", Burst: 15})
+	if err == nil {
+		t.Fatal("buildStore with rate 0 = nil error, want an error")
+	}
+}
+
+func TestBuildStoreUnknownAlgorithm(t *testing.T) {
+	_, err := buildStore(Rule{Algorithm: "bogus"})
+	if err == nil {
+		t.Fatal("buildStore with an unknown algorithm = nil error, want an error")
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// memoryStoreShards is the number of shards MemoryStore splits its visitor
+// map across. A single map guarded by one mutex serializes every cookie
+// lookup under concurrency; sharding spreads that contention across
+// independently-locked buckets.
+const memoryStoreShards = 256
+
+// defaultMaxVisitors bounds the total number of visitors MemoryStore keeps
+// across all shards, so a flood of unique remember_token values can't OOM
+// the proxy. It's split evenly across shards.
+const defaultMaxVisitors = 65536
+
+// defaultVisitorTTL is how long a visitor can sit idle before the sweeper
+// evicts it.
+const defaultVisitorTTL = 15 * time.Minute
+
+// Decision is the result of a Store.Allow check, carrying enough detail to
+// populate RateLimit-* and Retry-After response headers (per
+// draft-ietf-httpapi-ratelimit-headers) in addition to the plain allow/deny
+// outcome.
+type Decision struct {
+	Allowed bool
+	// Limit is the budget the rule enforces, e.g. burst size or requests
+	// per window.
+	Limit int
+	// Remaining is how much of Limit is left right now. Only meaningful
+	// when Allowed is true.
+	Remaining int
+	// RetryAfter is how long the caller should wait before retrying. Zero
+	// when Allowed is true.
+	RetryAfter time.Duration
+}
+
+// Store is a rate limiter backend keyed by an arbitrary identifier (the
+// "remember_token" cookie value in practice). Implementations must be safe
+// for concurrent use.
+type Store interface {
+	// Allow reports whether the request identified by key is permitted under
+	// the configured rate limit, consuming a token from the budget if so.
+	Allow(key string) (Decision, error)
+	// Reset clears any limiter state associated with key.
+	Reset(key string) error
+}
+
+// visitor is a single key's limiter plus when it was last used, so the
+// sweeper can evict idle keys and getLimiter can evict the least-recently-
+// used one when a shard is full.
+type visitor struct {
+	limiter *rate.Limiter
+	// lastSeen is a UnixNano timestamp, updated atomically so the getLimiter
+	// hot path doesn't need to take a write lock just to record usage.
+	lastSeen atomic.Int64
+}
+
+// memoryShard is one bucket of MemoryStore's sharded visitor map, with its
+// own lock so lookups against different shards never contend.
+type memoryShard struct {
+	mu       sync.RWMutex
+	visitors map[string]*visitor
+}
+
+// MemoryStore is the original in-process Store: a sharded map of
+// token-bucket limiters. It only sees traffic handled by this process, so
+// replicas behind a load balancer do not share limit state.
+type MemoryStore struct {
+	shards      [memoryStoreShards]*memoryShard
+	rate        rate.Limit
+	burst       int
+	maxPerShard int
+	ttl         time.Duration
+}
+
+// NewMemoryStore creates an in-process Store using the default visitor cap
+// and idle TTL.
+func NewMemoryStore(rl rate.Limit, burst int) *MemoryStore {
+	return NewMemoryStoreWithLimits(rl, burst, defaultMaxVisitors, defaultVisitorTTL)
+}
+
+// NewMemoryStoreWithLimits creates an in-process Store capped at maxVisitors
+// total visitors (split evenly across shards) and evicting entries idle for
+// longer than ttl.
+func NewMemoryStoreWithLimits(rl rate.Limit, burst, maxVisitors int, ttl time.Duration) *MemoryStore {
+	maxPerShard := maxVisitors / memoryStoreShards
+	if maxPerShard < 1 {
+		maxPerShard = 1
+	}
+
+	s := &MemoryStore{rate: rl, burst: burst, maxPerShard: maxPerShard, ttl: ttl}
+	for i := range s.shards {
+		s.shards[i] = &memoryShard{visitors: make(map[string]*visitor)}
+	}
+	return s
+}
+
+// shardFor returns the shard responsible for key.
+func (s *MemoryStore) shardFor(key string) *memoryShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return s.shards[h.Sum32()%memoryStoreShards]
+}
+
+// Allow implements Store.
+func (s *MemoryStore) Allow(key string) (Decision, error) {
+	lim := s.getLimiter(key)
+
+	r := lim.Reserve()
+	if !r.OK() {
+		return Decision{Limit: s.burst}, nil
+	}
+
+	if delay := r.Delay(); delay > 0 {
+		r.Cancel()
+		return Decision{Limit: s.burst, RetryAfter: delay}, nil
+	}
+
+	return Decision{Allowed: true, Limit: s.burst, Remaining: int(lim.Tokens())}, nil
+}
+
+// Reset implements Store.
+func (s *MemoryStore) Reset(key string) error {
+	shard := s.shardFor(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	delete(shard.visitors, key)
+	return nil
+}
+
+// getLimiter returns the rate limiter for the given key, creating a new one
+// if necessary, and records that key was just used. The common case (key
+// already has a visitor) only takes a read lock; the write lock is only
+// needed on a miss.
+func (s *MemoryStore) getLimiter(key string) *rate.Limiter {
+	now := time.Now().UnixNano()
+	shard := s.shardFor(key)
+
+	shard.mu.RLock()
+	v, exists := shard.visitors[key]
+	shard.mu.RUnlock()
+	if exists {
+		v.lastSeen.Store(now)
+		return v.limiter
+	}
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if v, exists := shard.visitors[key]; exists {
+		v.lastSeen.Store(now)
+		return v.limiter
+	}
+
+	if len(shard.visitors) >= s.maxPerShard {
+		shard.evictOldestLocked()
+	}
+
+	v = &visitor{limiter: rate.NewLimiter(s.rate, s.burst)}
+	v.lastSeen.Store(now)
+	shard.visitors[key] = v
+	return v.limiter
+}
+
+// evictOldestLocked removes the least-recently-used visitor from the shard.
+// The caller must hold shard.mu for writing.
+func (shard *memoryShard) evictOldestLocked() {
+	var oldestKey string
+	oldestAt := int64(math.MaxInt64)
+
+	for k, v := range shard.visitors {
+		if seen := v.lastSeen.Load(); seen < oldestAt {
+			oldestAt = seen
+			oldestKey = k
+		}
+	}
+
+	if oldestKey != "" {
+		delete(shard.visitors, oldestKey)
+	}
+}
+
+// Sweeper periodically evicts visitors that have been idle for longer than
+// the store's TTL, so that tokens for visitors who have stopped sending
+// requests eventually get garbage collected. Each shard's lock is held only
+// long enough to delete the keys already found to be expired.
+func (s *MemoryStore) Sweeper(interval time.Duration) {
+	t := time.NewTicker(interval)
+	for range t.C {
+		cutoff := time.Now().Add(-s.ttl).UnixNano()
+
+		for _, shard := range s.shards {
+			var expired []string
+
+			shard.mu.RLock()
+			for k, v := range shard.visitors {
+				if v.lastSeen.Load() < cutoff {
+					expired = append(expired, k)
+				}
+			}
+			shard.mu.RUnlock()
+
+			if len(expired) == 0 {
+				continue
+			}
+
+			shard.mu.Lock()
+			for _, k := range expired {
+				delete(shard.visitors, k)
+			}
+			shard.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestMemoryStoreAllow(t *testing.T) {
+	s := NewMemoryStoreWithLimits(rate.Every(time.Minute), 2, defaultMaxVisitors, defaultVisitorTTL)
+
+	for i := 0; i < 2; i++ {
+		d, err := s.Allow("a")
+		if err != nil || !d.Allowed {
+			t.Fatalf("request %d: got Decision %+v, err %v, want allowed", i, d, err)
+		}
+	}
+
+	d, err := s.Allow("a")
+	if err != nil || d.Allowed {
+		t.Fatalf("3rd request: got Decision %+v, err %v, want denied", d, err)
+	}
+	if d.RetryAfter <= 0 {
+		t.Errorf("RetryAfter = %v, want > 0 when denied", d.RetryAfter)
+	}
+
+	if err := s.Reset("a"); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if d, err := s.Allow("a"); err != nil || !d.Allowed {
+		t.Fatalf("after reset: got Decision %+v, err %v, want allowed", d, err)
+	}
+}
+
+func TestMemoryStoreEvictsLeastRecentlyUsedWhenShardFull(t *testing.T) {
+	// maxVisitors/memoryStoreShards rounds down, so pick a cap that yields
+	// exactly 1 visitor per shard.
+	s := NewMemoryStoreWithLimits(rate.Every(time.Minute), 1, memoryStoreShards, time.Hour)
+
+	shard := s.shardFor("a")
+	otherKeyInSameShard := ""
+	for i := 0; i < 10000; i++ {
+		candidate := fmt.Sprintf("key-%d", i)
+		if candidate != "a" && s.shardFor(candidate) == shard {
+			otherKeyInSameShard = candidate
+			break
+		}
+	}
+	if otherKeyInSameShard == "" {
+		t.Fatal("could not find a second key hashing to the same shard as \"a\"")
+	}
+
+	s.getLimiter("a")
+	s.getLimiter(otherKeyInSameShard)
+
+	shard.mu.RLock()
+	_, stillPresent := shard.visitors["a"]
+	n := len(shard.visitors)
+	shard.mu.RUnlock()
+
+	if n != 1 {
+		t.Fatalf("len(shard.visitors) = %d, want 1 (cap enforced)", n)
+	}
+	if stillPresent {
+		t.Errorf("least-recently-used key %q was not evicted from its shard", "a")
+	}
+}
+
+func TestMemoryStoreSweeperEvictsIdleVisitors(t *testing.T) {
+	s := NewMemoryStoreWithLimits(rate.Every(time.Minute), 1, defaultMaxVisitors, time.Millisecond)
+	s.getLimiter("a")
+
+	time.Sleep(5 * time.Millisecond)
+	go s.Sweeper(time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	shard := s.shardFor("a")
+	shard.mu.RLock()
+	_, exists := shard.visitors["a"]
+	shard.mu.RUnlock()
+	if exists {
+		t.Errorf("idle key %q should have been swept", "a")
+	}
+}